@@ -0,0 +1,180 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+// headerFlags collects repeated -header key=value flags. See main.go for
+// where it, and the other auth flags below, are registered.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ",") }
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("-header must be in the form key=value, got %q", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// resolveAuthHeaders builds the headers to send with a request to
+// targetURL, from -netrc, -auth-command and -header, applied in that order
+// so a repeatable -header always wins over the other two. The result is
+// only ever attached to the outgoing request (see withAuthHeaders); it must
+// never be written into commit meta.
+func resolveAuthHeaders(targetURL string) (map[string]string, error) {
+	headers := map[string]string{}
+
+	if *netrcFlag {
+		h, err := netrcHeaders(targetURL)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range h {
+			headers[k] = v
+		}
+	}
+
+	if *authCommandFlag != "" {
+		h, err := authCommandHeaders(*authCommandFlag)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range h {
+			headers[k] = v
+		}
+	}
+
+	for _, kv := range headerFlagsVal {
+		parts := strings.SplitN(kv, "=", 2)
+		headers[parts[0]] = parts[1]
+	}
+
+	return headers, nil
+}
+
+func netrcHeaders(targetURL string) (map[string]string, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	login, password, ok, err := lookupNetrc(filepath.Join(usr.HomeDir, ".netrc"), u.Hostname())
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	creds := base64.StdEncoding.EncodeToString([]byte(login + ":" + password))
+	return map[string]string{"Authorization": "Basic " + creds}, nil
+}
+
+// lookupNetrc does a minimal parse of a .netrc file, returning the login and
+// password of the entry for "machine host", if any.
+func lookupNetrc(path, host string) (login, password string, ok bool, err error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", false, nil
+		}
+		return "", "", false, err
+	}
+
+	fields := strings.Fields(string(contents))
+	matched := false
+	for i := 0; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "machine":
+			matched = fields[i+1] == host
+		case "login":
+			if matched {
+				login = fields[i+1]
+			}
+		case "password":
+			if matched {
+				password = fields[i+1]
+				ok = true
+			}
+		}
+	}
+	return login, password, ok, nil
+}
+
+// authCommandOutput is the JSON schema a -auth-command program must print
+// to stdout. Expires, when present, must be RFC3339; since a single fetch is
+// a one-shot use of the token, we don't act on it beyond warning if the
+// command handed us a token that's already expired.
+type authCommandOutput struct {
+	Headers map[string]string `json:"headers"`
+	Expires string            `json:"expires"`
+}
+
+func authCommandHeaders(cmd string) (map[string]string, error) {
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("-auth-command must not be empty")
+	}
+
+	out, err := exec.Command(parts[0], parts[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("-auth-command %q failed: %v", cmd, err)
+	}
+
+	var parsed authCommandOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("-auth-command %q printed invalid JSON: %v", cmd, err)
+	}
+	warnIfExpired(cmd, parsed.Expires)
+	return parsed.Headers, nil
+}
+
+// warnIfExpired prints a warning to stderr if expires (an RFC3339 timestamp
+// from a -auth-command's output) is already in the past. It's a warning
+// rather than an error because the fetch may still succeed (the command may
+// have been conservative, or the server may tolerate some clock skew), and
+// because a single fetch has no later point at which to refresh the token.
+func warnIfExpired(cmd, expires string) {
+	if expires == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: -auth-command %q printed an unparseable expires %q: %v\n", cmd, expires, err)
+		return
+	}
+	if t.Before(time.Now()) {
+		fmt.Fprintf(os.Stderr, "warning: -auth-command %q returned a token that already expired at %s\n", cmd, expires)
+	}
+}
+
+// stripAuthFields deletes any field from fields whose key is a header we
+// sent for authentication, so that credentials from -header, -netrc or
+// -auth-command can never end up persisted in commit meta even if a
+// Fetcher were to (incorrectly) echo a request header back as a meta field.
+func stripAuthFields(fields types.StructData, authHeaders map[string]string) {
+	delete(fields, "Authorization")
+	for k := range authHeaders {
+		delete(fields, k)
+	}
+}
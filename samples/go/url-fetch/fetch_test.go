@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/attic-labs/noms/go/datas"
@@ -172,6 +173,339 @@ func (s *testSuite) TestImportFromURLUsesEtag() {
 	assert.Equal(heightAfterFetch1, heightAfterFetch2)
 }
 
+func (s *testSuite) TestImportFromURLStoresLastModified() {
+	assert := s.Assert()
+	lastMod := "Wed, 21 Oct 2015 07:28:00 GMT"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastMod)
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+	s.MustRun(main, []string{svr.URL, dsName})
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	ds := sp.GetDatabase().GetDataset("ds")
+
+	expected := types.NewBlob(ds.Database(), bytes.NewBufferString("abcdef"))
+	assert.True(expected.Equals(sp.GetValue()))
+
+	meta := ds.Head().Get(datas.MetaField).(types.Struct)
+	metaDesc := types.TypeOf(meta).Desc.(types.StructDesc)
+	assert.Equal(3, metaDesc.Len())
+	assert.NotNil(metaDesc.Field("date"))
+	assert.Equal(svr.URL, string(meta.Get("url").(types.String)))
+	assert.Equal(lastMod, string(meta.Get("lastModified").(types.String)))
+}
+
+func (s *testSuite) TestImportFromURLUsesLastModified() {
+	assert := s.Assert()
+	lastMod := "Wed, 21 Oct 2015 07:28:00 GMT"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastMod {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastMod)
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	// First fetch commits and stores lastModified
+	s.MustRun(main, []string{svr.URL, dsName})
+	heightAfterFetch1 := s.commitHeight(dsName)
+
+	// Second fetch should use If-Modified-Since and will not commit
+	s.MustRun(main, []string{svr.URL, dsName})
+	heightAfterFetch2 := s.commitHeight(dsName)
+
+	assert.Equal(heightAfterFetch1, heightAfterFetch2)
+}
+
+func (s *testSuite) TestImportFromURLUsesEtagAndLastModified() {
+	assert := s.Assert()
+	lastMod := "Wed, 21 Oct 2015 07:28:00 GMT"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "xyz123" && r.Header.Get("If-Modified-Since") == lastMod {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", "xyz123")
+		w.Header().Set("Last-Modified", lastMod)
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	// First fetch commits and stores both etag and lastModified
+	s.MustRun(main, []string{svr.URL, dsName})
+	heightAfterFetch1 := s.commitHeight(dsName)
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	ds := sp.GetDatabase().GetDataset("ds")
+	meta := ds.Head().Get(datas.MetaField).(types.Struct)
+	metaDesc := types.TypeOf(meta).Desc.(types.StructDesc)
+	assert.Equal(4, metaDesc.Len())
+	assert.Equal("xyz123", string(meta.Get("etag").(types.String)))
+	assert.Equal(lastMod, string(meta.Get("lastModified").(types.String)))
+	sp.Close()
+
+	// Second fetch sends both conditional headers and will not commit
+	s.MustRun(main, []string{svr.URL, dsName})
+	heightAfterFetch2 := s.commitHeight(dsName)
+
+	assert.Equal(heightAfterFetch1, heightAfterFetch2)
+}
+
+func (s *testSuite) TestImportFromURLResumesRange() {
+	assert := s.Assert()
+	full := "abcdefghij"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Etag", "static-etag")
+		if rng := r.Header.Get("Range"); rng == "bytes=4-" && r.Header.Get("If-Range") == "static-etag" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 4-%d/%d", len(full)-1, len(full)))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, full[4:])
+			return
+		}
+		// Simulate an interrupted transfer: only the first 4 bytes make it
+		// out before the connection would have dropped.
+		fmt.Fprint(w, full[:4])
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	// First fetch only gets the first 4 bytes.
+	s.MustRun(main, []string{svr.URL, dsName})
+
+	// Second fetch resumes from byte 4 and completes the blob.
+	s.MustRun(main, []string{svr.URL, dsName})
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	expected := types.NewBlob(sp.GetDatabase(), bytes.NewBufferString(full))
+	assert.True(expected.Equals(sp.GetValue()))
+}
+
+func (s *testSuite) TestImportFromURLResumedRangeMatchesSingleFetch() {
+	assert := s.Assert()
+	full := "the quick brown fox jumps over the lazy dog"
+	resumableSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Etag", "static-etag")
+		if rng := r.Header.Get("Range"); rng == "bytes=10-" && r.Header.Get("If-Range") == "static-etag" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(full)-1, len(full)))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, full[10:])
+			return
+		}
+		fmt.Fprint(w, full[:10])
+	}))
+	defer resumableSvr.Close()
+
+	singleSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, full)
+	}))
+	defer singleSvr.Close()
+
+	resumedDsName := spec.CreateValueSpecString("nbs", s.DBDir, "resumed")
+	s.MustRun(main, []string{resumableSvr.URL, resumedDsName})
+	s.MustRun(main, []string{resumableSvr.URL, resumedDsName})
+
+	singleDsName := spec.CreateValueSpecString("nbs", s.DBDir, "single")
+	s.MustRun(main, []string{singleSvr.URL, singleDsName})
+
+	resumedSp, err := spec.ForPath(resumedDsName + ".value")
+	assert.NoError(err)
+	defer resumedSp.Close()
+
+	singleSp, err := spec.ForPath(singleDsName + ".value")
+	assert.NoError(err)
+	defer singleSp.Close()
+
+	assert.True(singleSp.GetValue().Equals(resumedSp.GetValue()))
+}
+
+func (s *testSuite) TestImportFromURLRangeFallsBackWhenResourceChanges() {
+	assert := s.Assert()
+	currentBody, currentEtag := "abcdefghij", "etag-v1"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Etag", currentEtag)
+		if rng := r.Header.Get("Range"); rng != "" && r.Header.Get("If-Range") == currentEtag {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %s/%d", rng[len("bytes="):], len(currentBody)))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, currentBody[len(currentBody):])
+			return
+		}
+		// No Range, or an If-Range that no longer matches: send the whole
+		// (possibly new) body.
+		fmt.Fprint(w, currentBody)
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+	s.MustRun(main, []string{svr.URL, dsName})
+
+	// The resource changes underneath us: new body, new etag. The fetcher
+	// sends its stale etag as If-Range, so the server answers with a full 200
+	// of the new body rather than a 206; the fetcher must take that as a full
+	// replacement instead of splicing old and new bytes.
+	currentBody, currentEtag = "0123456789", "etag-v2"
+	s.MustRun(main, []string{svr.URL, dsName})
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	expected := types.NewBlob(sp.GetDatabase(), bytes.NewBufferString(currentBody))
+	assert.True(expected.Equals(sp.GetValue()))
+}
+
+func (s *testSuite) TestImportFromURLWithHeaderAuth() {
+	assert := s.Assert()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+	s.MustRun(main, []string{"--header", "Authorization=Bearer secret-token", svr.URL, dsName})
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	ds := sp.GetDatabase().GetDataset("ds")
+	expected := types.NewBlob(ds.Database(), bytes.NewBufferString("abcdef"))
+	assert.True(expected.Equals(sp.GetValue()))
+
+	meta := ds.Head().Get(datas.MetaField).(types.Struct)
+	_, hasAuthField := meta.MaybeGet("Authorization")
+	assert.False(hasAuthField, "auth header must not be persisted into commit meta")
+}
+
+func (s *testSuite) TestImportFromURLWithAuthCommand() {
+	assert := s.Assert()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer from-auth-command" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dir, err := ioutil.TempDir("", "TestImportFromURLWithAuthCommand")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	authCommand := filepath.Join(dir, "auth-command.sh")
+	script := "#!/bin/sh\necho '{\"headers\": {\"Authorization\": \"Bearer from-auth-command\"}}'\n"
+	assert.NoError(ioutil.WriteFile(authCommand, []byte(script), 0755))
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+	s.MustRun(main, []string{"--auth-command", authCommand, svr.URL, dsName})
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	ds := sp.GetDatabase().GetDataset("ds")
+	expected := types.NewBlob(ds.Database(), bytes.NewBufferString("abcdef"))
+	assert.True(expected.Equals(sp.GetValue()))
+
+	meta := ds.Head().Get(datas.MetaField).(types.Struct)
+	_, hasAuthField := meta.MaybeGet("Authorization")
+	assert.False(hasAuthField, "auth header must not be persisted into commit meta")
+}
+
+func (s *testSuite) TestImportFromURLDedupSkipsIdenticalBody() {
+	assert := s.Assert()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	s.MustRun(main, []string{"--dedup", svr.URL, dsName})
+	heightAfterFetch1 := s.commitHeight(dsName)
+
+	// Second fetch gets identical bytes, so -dedup skips the commit.
+	s.MustRun(main, []string{"--dedup", svr.URL, dsName})
+	heightAfterFetch2 := s.commitHeight(dsName)
+
+	assert.Equal(heightAfterFetch1, heightAfterFetch2)
+}
+
+func (s *testSuite) TestImportFromURLDedupCommitsOnChange() {
+	assert := s.Assert()
+	body := "abcdef"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	s.MustRun(main, []string{"--dedup", svr.URL, dsName})
+	heightAfterFetch1 := s.commitHeight(dsName)
+
+	body = "ghijkl"
+	s.MustRun(main, []string{"--dedup", svr.URL, dsName})
+	heightAfterFetch2 := s.commitHeight(dsName)
+
+	assert.NotEqual(heightAfterFetch1, heightAfterFetch2)
+}
+
+func (s *testSuite) TestImportFromURLDedupTouchUpdatesLastChecked() {
+	assert := s.Assert()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "abcdef")
+	}))
+	defer svr.Close()
+
+	dsName := spec.CreateValueSpecString("nbs", s.DBDir, "ds")
+
+	s.MustRun(main, []string{"--dedup", svr.URL, dsName})
+	heightAfterFetch1 := s.commitHeight(dsName)
+
+	// Bytes are unchanged, but -touch still makes an amend-style commit.
+	s.MustRun(main, []string{"--dedup", "--touch", svr.URL, dsName})
+	heightAfterFetch2 := s.commitHeight(dsName)
+
+	assert.NotEqual(heightAfterFetch1, heightAfterFetch2)
+
+	sp, err := spec.ForPath(dsName + ".value")
+	assert.NoError(err)
+	defer sp.Close()
+
+	ds := sp.GetDatabase().GetDataset("ds")
+	expected := types.NewBlob(ds.Database(), bytes.NewBufferString("abcdef"))
+	assert.True(expected.Equals(sp.GetValue()))
+
+	meta := ds.Head().Get(datas.MetaField).(types.Struct)
+	_, hasLastChecked := meta.MaybeGet("lastChecked")
+	assert.True(hasLastChecked)
+}
+
 func (s *testSuite) TestImportFromURLCommitsMultiple() {
 	assert := s.Assert()
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
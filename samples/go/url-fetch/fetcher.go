@@ -0,0 +1,98 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+// Fetcher knows how to retrieve the bytes at a URL for one scheme (http,
+// s3, gs, ssh, ...) along with any scheme-specific fields worth recording
+// on the commit meta. Fetch may consult ctx for details of the previous
+// fetch (see withPreviousFetch) to support conditional and resumable
+// fetches; when it determines the resource is unchanged since then, it
+// returns unchanged=true and nil r/meta.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (r io.ReadCloser, meta map[string]types.Value, unchanged bool, err error)
+}
+
+// fetchers maps URL scheme (e.g. "s3") to the Fetcher that handles it.
+// Built-in fetchers register themselves from init() in their own files.
+var fetchers = map[string]Fetcher{}
+
+// registerFetcher makes f the Fetcher used for URLs with the given scheme.
+// It panics on a duplicate registration, since that always indicates a
+// programming error rather than something callers should recover from.
+func registerFetcher(scheme string, f Fetcher) {
+	if _, ok := fetchers[scheme]; ok {
+		panic(fmt.Sprintf("fetcher already registered for scheme %q", scheme))
+	}
+	fetchers[scheme] = f
+}
+
+type previousFetchKey int
+
+const (
+	previousMetaKey previousFetchKey = iota
+	previousBlobKey
+	authHeadersKey
+)
+
+// withPreviousFetch attaches the meta and/or Blob value of the dataset's
+// current head to ctx, so a Fetcher can make conditional-fetch and resume
+// decisions without needing its own access to the Dataset.
+func withPreviousFetch(ctx context.Context, meta types.Struct, hasMeta bool, blob types.Blob, hasBlob bool) context.Context {
+	if hasMeta {
+		ctx = context.WithValue(ctx, previousMetaKey, meta)
+	}
+	if hasBlob {
+		ctx = context.WithValue(ctx, previousBlobKey, blob)
+	}
+	return ctx
+}
+
+func previousMetaFromContext(ctx context.Context) (types.Struct, bool) {
+	m, ok := ctx.Value(previousMetaKey).(types.Struct)
+	return m, ok
+}
+
+func previousBlobFromContext(ctx context.Context) (types.Blob, bool) {
+	b, ok := ctx.Value(previousBlobKey).(types.Blob)
+	return b, ok
+}
+
+// withAuthHeaders attaches extra headers (from --header, --netrc and
+// --auth-command; see auth.go) that a Fetcher should merge into its
+// outgoing request, but must never copy into commit meta.
+func withAuthHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, authHeadersKey, headers)
+}
+
+func authHeadersFromContext(ctx context.Context) map[string]string {
+	h, _ := ctx.Value(authHeadersKey).(map[string]string)
+	return h
+}
+
+// parseBucketURL splits a "scheme://bucket/key" URL into its bucket and key
+// parts, as used by both the s3:// and gs:// fetchers.
+func parseBucketURL(rawURL string) (bucket, key string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == "" || u.Path == "" {
+		return "", "", fmt.Errorf("invalid %s URL %q: expected %s://bucket/key", u.Scheme, rawURL, u.Scheme)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
@@ -0,0 +1,86 @@
+// +build gcs
+
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// package main's gcs fetcher is only compiled in (and these tests only run)
+// with `go test -tags gcs ./...`; a plain `go test ./...` skips this file
+// entirely, so CI must include the tagged run to get gcs coverage.
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGCSObject struct {
+	Body       []byte
+	Generation int64
+}
+
+type fakeGCSClient struct {
+	objects map[string]fakeGCSObject
+}
+
+func (f fakeGCSClient) Attrs(bucket, object string) (int64, error) {
+	return f.objects[bucket+"/"+object].Generation, nil
+}
+
+func (f fakeGCSClient) GetObject(bucket, object string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.objects[bucket+"/"+object].Body)), nil
+}
+
+func TestGCSFetcherRecordsGeneration(t *testing.T) {
+	client := fakeGCSClient{objects: map[string]fakeGCSObject{
+		"my-bucket/my-object": {
+			Body:       []byte("abcdef"),
+			Generation: 7,
+		},
+	}}
+
+	r, meta, unchanged, err := gcsFetcher{client: client}.Fetch(context.Background(), "gs://my-bucket/my-object")
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+	assert.Equal(t, types.Number(7), meta["gcsGeneration"])
+
+	body, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef", string(body))
+}
+
+func TestGCSFetcherSkipsUnchangedGenerationWithoutDownloadingBody(t *testing.T) {
+	client := &countingFakeGCSClient{fakeGCSClient{objects: map[string]fakeGCSObject{
+		"my-bucket/my-object": {
+			Body:       []byte("abcdef"),
+			Generation: 7,
+		},
+	}}}
+
+	prevMeta := types.NewStruct("", types.StructData{"gcsGeneration": types.Number(7)})
+	ctx := withPreviousFetch(context.Background(), prevMeta, true, types.Blob{}, false)
+
+	r, _, unchanged, err := gcsFetcher{client: client}.Fetch(ctx, "gs://my-bucket/my-object")
+	assert.NoError(t, err)
+	assert.True(t, unchanged)
+	assert.Nil(t, r)
+	assert.Equal(t, 0, client.getObjectCalls)
+}
+
+// countingFakeGCSClient counts GetObject calls so the unchanged-skip test
+// can assert the body was never opened, not merely that it wasn't returned.
+type countingFakeGCSClient struct {
+	fakeGCSClient
+	getObjectCalls int
+}
+
+func (c *countingFakeGCSClient) GetObject(bucket, object string) (io.ReadCloser, error) {
+	c.getObjectCalls++
+	return c.fakeGCSClient.GetObject(bucket, object)
+}
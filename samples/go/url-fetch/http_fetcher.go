@@ -0,0 +1,164 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+func init() {
+	registerFetcher("http", httpFetcher{})
+	registerFetcher("https", httpFetcher{})
+}
+
+// httpFetcher fetches over plain HTTP(S). It sends If-None-Match /
+// If-Modified-Since headers derived from the previous fetch's meta so a
+// server which hasn't changed the resource can reply 304, and resumes via
+// Range when the previous fetch recorded that the server advertised
+// Accept-Ranges and we have a validator to send as If-Range — without a
+// validator there's no way to tell a resumed response from a confusingly
+// similar response to a since-changed resource, so a plain full fetch is
+// used instead.
+type httpFetcher struct{}
+
+func (httpFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, map[string]types.Value, bool, error) {
+	prevEtag, prevLastMod := "", ""
+	prevAcceptRanges := false
+	if meta, ok := previousMetaFromContext(ctx); ok {
+		prevEtag = getMetaString(meta, "etag")
+		prevLastMod = getMetaString(meta, "lastModified")
+		prevAcceptRanges = getMetaBool(meta, "acceptRanges")
+	}
+	prevBlob, hasPrev := previousBlobFromContext(ctx)
+
+	validator := prevEtag
+	if validator == "" {
+		validator = prevLastMod
+	}
+	attemptResume := hasPrev && prevAcceptRanges && validator != ""
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if prevEtag != "" {
+		req.Header.Set("If-None-Match", prevEtag)
+	}
+	if prevLastMod != "" {
+		req.Header.Set("If-Modified-Since", prevLastMod)
+	}
+	if attemptResume {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", prevBlob.Len()))
+		// If-Range makes the Range conditional on the resource still being the
+		// one we partially have; if it changed, the server must send a full
+		// 200 response instead of a 206 we'd otherwise wrongly splice onto our
+		// stale prefix.
+		req.Header.Set("If-Range", validator)
+	}
+	for k, v := range authHeadersFromContext(ctx) {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, nil, true, nil
+	}
+
+	meta := map[string]types.Value{}
+	if etag := resp.Header.Get("Etag"); etag != "" {
+		meta["etag"] = types.String(etag)
+	}
+	if lastMod := resp.Header.Get("Last-Modified"); lastMod != "" {
+		meta["lastModified"] = types.String(lastMod)
+	}
+	if resp.Header.Get("Accept-Ranges") == "bytes" {
+		meta["acceptRanges"] = types.Bool(true)
+	}
+
+	// A 200 in response to a Range+If-Range request means the resource
+	// changed (or the server ignored the range), so fall back to a full
+	// replacement fetch rather than appending to what we already have.
+	resumed := resp.StatusCode == http.StatusPartialContent && attemptResume
+	var body io.Reader = resp.Body
+	if resumed {
+		body = io.MultiReader(prevBlob.Reader(), resp.Body)
+	}
+
+	want, haveWant := expectedContentLength(resp, resumed)
+	r := &lengthCheckedReadCloser{r: body, c: resp.Body, want: want, haveWant: haveWant, url: url}
+	return r, meta, false, nil
+}
+
+// expectedContentLength returns the total byte length the fetched blob
+// should have once fully read, derived from Content-Range for a resumed
+// (206) response or Content-Length for a full (200) one. ok is false if the
+// server didn't supply enough information to check.
+func expectedContentLength(resp *http.Response, resumed bool) (total uint64, ok bool) {
+	if resumed {
+		cr := resp.Header.Get("Content-Range")
+		idx := strings.LastIndex(cr, "/")
+		if idx == -1 || cr[idx+1:] == "*" {
+			return 0, false
+		}
+		total, err := strconv.ParseUint(cr[idx+1:], 10, 64)
+		return total, err == nil
+	}
+	cl := resp.Header.Get("Content-Length")
+	if cl == "" {
+		return 0, false
+	}
+	total, err := strconv.ParseUint(cl, 10, 64)
+	return total, err == nil
+}
+
+func getMetaBool(meta types.Struct, field string) bool {
+	if v, ok := meta.MaybeGet(field); ok {
+		if b, ok := v.(types.Bool); ok {
+			return bool(b)
+		}
+	}
+	return false
+}
+
+// lengthCheckedReadCloser wraps a reader/closer pair and, once the expected
+// total (when known) has been read, verifies the actual byte count matches.
+// The check happens as part of Read rather than Close: NewBlob drains the
+// reader to EOF before db.CommitValue is ever called, so surfacing a length
+// mismatch as a read error (instead of a clean EOF) makes blob construction
+// itself fail, guaranteeing a short or corrupt transfer never reaches
+// CommitValue in the first place.
+type lengthCheckedReadCloser struct {
+	r        io.Reader
+	c        io.Closer
+	n        int64
+	want     uint64
+	haveWant bool
+	url      string
+}
+
+func (l *lengthCheckedReadCloser) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if err == io.EOF && l.haveWant && uint64(l.n) != l.want {
+		return n, fmt.Errorf("fetched %d bytes from %s, expected %d", l.n, l.url, l.want)
+	}
+	return n, err
+}
+
+func (l *lengthCheckedReadCloser) Close() error {
+	return l.c.Close()
+}
@@ -0,0 +1,239 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// fetch imports the contents of stdin, a local file, or a URL into a Noms
+// blob, recording enough metadata about the source to allow later runs to
+// detect that nothing has changed and skip creating a new commit. URLs are
+// dispatched to a Fetcher registered for their scheme (see fetcher.go);
+// http(s) is supported out of the box. s3, gs and ssh pull in the AWS,
+// Google Cloud and golang.org/x/crypto/ssh SDKs respectively, so each is
+// only compiled in (and its scheme registered) when built with the matching
+// tag, e.g. `go build -tags "s3 gcs ssh"`; without those tags, fetching an
+// s3://, gs:// or ssh:// URL fails with "no fetcher registered for scheme".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/spec"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// Flags are (re-)registered on a fresh FlagSet at the top of every main()
+// call rather than on flag.CommandLine, so that repeated invocations in the
+// same process (as clienttest does) don't see flags or repeatable -header
+// values left over from a previous run.
+var (
+	stdinFlag       *bool
+	dedupFlag       *bool
+	touchFlag       *bool
+	netrcFlag       *bool
+	authCommandFlag *string
+	headerFlagsVal  headerFlags
+)
+
+func main() {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: fetch [options] <path-or-url> <dataset>")
+		fmt.Fprintln(os.Stderr, "       fetch [options] --stdin <dataset>")
+		fmt.Fprintln(os.Stderr, "s3://, gs:// and ssh:// URLs require building with -tags \"s3 gcs ssh\" (as needed); plain http(s) needs no tags.")
+		fs.PrintDefaults()
+	}
+	stdinFlag = fs.Bool("stdin", false, "read the blob contents from stdin rather than a file or URL")
+	dedupFlag = fs.Bool("dedup", false, "skip committing when the fetched bytes match the current head's value")
+	touchFlag = fs.Bool("touch", false, "with -dedup, still make an amend-style commit that updates lastChecked when bytes are unchanged")
+	netrcFlag = fs.Bool("netrc", false, "load Basic-auth credentials for the target host from ~/.netrc")
+	authCommandFlag = fs.String("auth-command", "", "run this command and merge the JSON {\"headers\": {...}} it prints to stdout into the request")
+	headerFlagsVal = nil
+	fs.Var(&headerFlagsVal, "header", "extra request header in key=value form; may be repeated")
+	fs.Parse(os.Args[1:])
+
+	var source, dsSpecStr string
+	if *stdinFlag {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(1)
+		}
+		dsSpecStr = fs.Arg(0)
+	} else {
+		if fs.NArg() != 2 {
+			fs.Usage()
+			os.Exit(1)
+		}
+		source, dsSpecStr = fs.Arg(0), fs.Arg(1)
+	}
+
+	sp, err := spec.ForDataset(dsSpecStr)
+	d.CheckError(err)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	ds := sp.GetDataset()
+
+	switch {
+	case *stdinFlag:
+		commitBlob(db, ds, os.Stdin, types.NewStruct("", types.StructData{
+			"date": types.String(nowISO8601()),
+		}))
+	case isURL(source):
+		commitFromURL(db, ds, source)
+	default:
+		f, err := os.Open(source)
+		d.CheckError(err)
+		defer f.Close()
+		commitBlob(db, ds, f, types.NewStruct("", types.StructData{
+			"date": types.String(nowISO8601()),
+			"file": types.String(source),
+		}))
+	}
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// commitFromURL fetches u via the Fetcher registered for its scheme and, if
+// the Fetcher reports the resource is unchanged since the current head,
+// skips the commit entirely.
+func commitFromURL(db datas.Database, ds datas.Dataset, u string) {
+	parsed, err := url.Parse(u)
+	d.CheckError(err)
+
+	f, ok := fetchers[parsed.Scheme]
+	if !ok {
+		d.CheckError(fmt.Errorf("no fetcher registered for scheme %q", parsed.Scheme))
+	}
+
+	authHeaders, err := resolveAuthHeaders(u)
+	d.CheckError(err)
+
+	ctx := context.Background()
+	meta, hasMeta := currentMeta(ds)
+	blob, hasBlob := currentBlob(ds)
+	ctx = withPreviousFetch(ctx, meta, hasMeta, blob, hasBlob)
+	ctx = withAuthHeaders(ctx, authHeaders)
+
+	r, fetchedMeta, unchanged, err := f.Fetch(ctx, u)
+	d.CheckError(err)
+	if unchanged {
+		return
+	}
+	defer func() {
+		d.CheckError(r.Close())
+	}()
+
+	fields := types.StructData{
+		"date": types.String(nowISO8601()),
+		"url":  types.String(u),
+	}
+	for k, v := range fetchedMeta {
+		fields[k] = v
+	}
+	stripAuthFields(fields, authHeaders)
+
+	commitBlob(db, ds, r, types.NewStruct("", fields))
+}
+
+// commitBlob streams r into a Blob and commits it as ds's new head. With
+// -dedup, if the resulting Blob's hash matches the current head's value, the
+// commit is skipped entirely (Noms blobs are already content-addressed via
+// the prolly tree, so the comparison is a single hash equality check); with
+// -touch, an unchanged blob still gets an amend-style commit that copies the
+// existing meta forward with a fresh lastChecked field, so a dataset can
+// record "still current as of" without bumping its content.
+func commitBlob(db datas.Database, ds datas.Dataset, r io.Reader, meta types.Struct) {
+	blob := types.NewBlob(db, r)
+
+	if *dedupFlag {
+		if prevBlob, ok := currentBlob(ds); ok && prevBlob.Hash() == blob.Hash() {
+			if *touchFlag {
+				touchLastChecked(db, ds)
+			}
+			return
+		}
+	}
+
+	_, err := db.CommitValue(ds, blob, datas.CommitOptions{Meta: meta})
+	d.CheckError(err)
+}
+
+// metaFieldNames enumerates every field this tool writes to a commit's meta
+// struct other than lastChecked (which touchLastChecked always sets afresh),
+// so an amend-style -touch commit can copy them forward without needing
+// generic struct introspection.
+var metaFieldNames = []string{
+	"date", "url", "file", "etag", "lastModified", "acceptRanges",
+	"s3Etag", "s3VersionId", "gcsGeneration", "sshHostKeyFingerprint",
+}
+
+// touchLastChecked makes a new commit whose value is unchanged from ds's
+// current head but whose meta has a fresh lastChecked field, recording that
+// a -dedup fetch confirmed the source hasn't changed.
+func touchLastChecked(db datas.Database, ds datas.Dataset) {
+	prevMeta, ok := currentMeta(ds)
+	if !ok {
+		return
+	}
+	blob, ok := currentBlob(ds)
+	if !ok {
+		return
+	}
+
+	fields := types.StructData{}
+	for _, name := range metaFieldNames {
+		if v, ok := prevMeta.MaybeGet(name); ok {
+			fields[name] = v
+		}
+	}
+	fields["lastChecked"] = types.String(nowISO8601())
+
+	_, err := db.CommitValue(ds, blob, datas.CommitOptions{Meta: types.NewStruct("", fields)})
+	d.CheckError(err)
+}
+
+// currentMeta returns the meta struct of ds's current head, and whether ds
+// has a head at all.
+func currentMeta(ds datas.Dataset) (types.Struct, bool) {
+	head, ok := ds.MaybeHead()
+	if !ok {
+		return types.Struct{}, false
+	}
+	meta, ok := head.MaybeGet(datas.MetaField)
+	if !ok {
+		return types.Struct{}, false
+	}
+	return meta.(types.Struct), true
+}
+
+// currentBlob returns the Blob value of ds's current head, if the head
+// exists and its value is in fact a Blob.
+func currentBlob(ds datas.Dataset) (types.Blob, bool) {
+	v, ok := ds.MaybeHeadValue()
+	if !ok {
+		return types.Blob{}, false
+	}
+	b, ok := v.(types.Blob)
+	return b, ok
+}
+
+func getMetaString(meta types.Struct, field string) string {
+	if v, ok := meta.MaybeGet(field); ok {
+		return string(v.(types.String))
+	}
+	return ""
+}
+
+func nowISO8601() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
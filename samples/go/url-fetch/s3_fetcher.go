@@ -0,0 +1,105 @@
+// +build s3
+
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+func init() {
+	registerFetcher("s3", s3Fetcher{client: realS3Client{}})
+}
+
+// s3Client separates the metadata-only HeadObject from GetObject so
+// s3Fetcher can detect an unchanged object without ever opening its body.
+type s3Client interface {
+	HeadObject(bucket, key string) (etag, versionID string, err error)
+	GetObject(bucket, key string) (io.ReadCloser, error)
+}
+
+// s3Fetcher fetches objects from S3 addressed as s3://bucket/key, recording
+// the object's ETag and (when the bucket is versioned) version ID as
+// commit meta.
+type s3Fetcher struct {
+	client s3Client
+}
+
+func (f s3Fetcher) Fetch(ctx context.Context, rawURL string) (io.ReadCloser, map[string]types.Value, bool, error) {
+	bucket, key, err := parseBucketURL(rawURL)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	etag, versionID, err := f.client.HeadObject(bucket, key)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if prevMeta, ok := previousMetaFromContext(ctx); ok && etag != "" && getMetaString(prevMeta, "s3Etag") == etag {
+		return nil, nil, true, nil
+	}
+
+	body, err := f.client.GetObject(bucket, key)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	meta := map[string]types.Value{}
+	if etag != "" {
+		meta["s3Etag"] = types.String(etag)
+	}
+	if versionID != "" {
+		meta["s3VersionId"] = types.String(versionID)
+	}
+	return body, meta, false, nil
+}
+
+type realS3Client struct{}
+
+func (realS3Client) HeadObject(bucket, key string) (etag, versionID string, err error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", "", err
+	}
+	out, err := s3.New(sess).HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if out.ETag != nil {
+		etag = strings.Trim(*out.ETag, `"`)
+	}
+	if out.VersionId != nil {
+		versionID = *out.VersionId
+	}
+	return etag, versionID, nil
+}
+
+func (realS3Client) GetObject(bucket, key string) (io.ReadCloser, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s3.New(sess).GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
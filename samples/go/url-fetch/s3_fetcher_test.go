@@ -0,0 +1,90 @@
+// +build s3
+
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// package main's s3 fetcher is only compiled in (and these tests only run)
+// with `go test -tags s3 ./...`; a plain `go test ./...` skips this file
+// entirely, so CI must include the tagged run to get s3 coverage.
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeS3Object struct {
+	Body      []byte
+	ETag      string
+	VersionID string
+}
+
+type fakeS3Client struct {
+	objects map[string]fakeS3Object
+}
+
+func (f fakeS3Client) HeadObject(bucket, key string) (etag, versionID string, err error) {
+	obj := f.objects[bucket+"/"+key]
+	return obj.ETag, obj.VersionID, nil
+}
+
+func (f fakeS3Client) GetObject(bucket, key string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.objects[bucket+"/"+key].Body)), nil
+}
+
+func TestS3FetcherRecordsEtagAndVersion(t *testing.T) {
+	client := fakeS3Client{objects: map[string]fakeS3Object{
+		"my-bucket/my-key": {
+			Body:      []byte("abcdef"),
+			ETag:      "etag123",
+			VersionID: "v1",
+		},
+	}}
+
+	r, meta, unchanged, err := s3Fetcher{client: client}.Fetch(context.Background(), "s3://my-bucket/my-key")
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+	assert.Equal(t, types.String("etag123"), meta["s3Etag"])
+	assert.Equal(t, types.String("v1"), meta["s3VersionId"])
+
+	body, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef", string(body))
+}
+
+func TestS3FetcherSkipsUnchangedEtagWithoutDownloadingBody(t *testing.T) {
+	client := &countingFakeS3Client{fakeS3Client{objects: map[string]fakeS3Object{
+		"my-bucket/my-key": {
+			Body: []byte("abcdef"),
+			ETag: "etag123",
+		},
+	}}}
+
+	prevMeta := types.NewStruct("", types.StructData{"s3Etag": types.String("etag123")})
+	ctx := withPreviousFetch(context.Background(), prevMeta, true, types.Blob{}, false)
+
+	r, _, unchanged, err := s3Fetcher{client: client}.Fetch(ctx, "s3://my-bucket/my-key")
+	assert.NoError(t, err)
+	assert.True(t, unchanged)
+	assert.Nil(t, r)
+	assert.Equal(t, 0, client.getObjectCalls)
+}
+
+// countingFakeS3Client counts GetObject calls so the unchanged-skip test can
+// assert the body was never opened, not merely that it wasn't returned.
+type countingFakeS3Client struct {
+	fakeS3Client
+	getObjectCalls int
+}
+
+func (c *countingFakeS3Client) GetObject(bucket, key string) (io.ReadCloser, error) {
+	c.getObjectCalls++
+	return c.fakeS3Client.GetObject(bucket, key)
+}
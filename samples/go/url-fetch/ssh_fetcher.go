@@ -0,0 +1,146 @@
+// +build ssh
+
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+func init() {
+	registerFetcher("ssh", sshFetcher{client: realSSHClient{}})
+}
+
+// sshObject is the subset of a remote file read that sshFetcher cares
+// about. realSSHClient backs it with a real SSH connection; tests
+// substitute a fake client.
+type sshObject struct {
+	Body               io.ReadCloser
+	HostKeyFingerprint string
+}
+
+type sshClient interface {
+	ReadFile(host, path string) (sshObject, error)
+}
+
+// sshFetcher fetches a remote file addressed as ssh://host/path by opening
+// an SSH session and running `cat`, in the spirit of scp. It records the
+// server's host key fingerprint as commit meta so a change of host (or a
+// MITM) is visible in the dataset's history.
+type sshFetcher struct {
+	client sshClient
+}
+
+func (f sshFetcher) Fetch(ctx context.Context, rawURL string) (io.ReadCloser, map[string]types.Value, bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	obj, err := f.client.ReadFile(u.Host, u.Path)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	meta := map[string]types.Value{}
+	if obj.HostKeyFingerprint != "" {
+		meta["sshHostKeyFingerprint"] = types.String(obj.HostKeyFingerprint)
+	}
+	return obj.Body, meta, false, nil
+}
+
+type realSSHClient struct{}
+
+func (realSSHClient) ReadFile(host, path string) (sshObject, error) {
+	var fingerprint string
+	config := &ssh.ClientConfig{
+		User: sshUser(),
+		Auth: []ssh.AuthMethod{ssh.PublicKeysCallback(sshAgentSigners)},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint = ssh.FingerprintSHA256(key)
+			return nil
+		},
+	}
+
+	client, err := ssh.Dial("tcp", withDefaultSSHPort(host), config)
+	if err != nil {
+		return sshObject{}, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return sshObject{}, err
+	}
+	out, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		client.Close()
+		return sshObject{}, err
+	}
+	if err := session.Start(fmt.Sprintf("cat %q", path)); err != nil {
+		session.Close()
+		client.Close()
+		return sshObject{}, err
+	}
+
+	return sshObject{
+		Body:               &sshSessionReadCloser{Reader: out, session: session, client: client},
+		HostKeyFingerprint: fingerprint,
+	}, nil
+}
+
+// sshSessionReadCloser makes the stdout of a running `cat` session
+// io.ReadCloser, tearing down the session and the underlying connection
+// once the caller is done reading.
+type sshSessionReadCloser struct {
+	io.Reader
+	session *ssh.Session
+	client  *ssh.Client
+}
+
+func (s *sshSessionReadCloser) Close() error {
+	waitErr := s.session.Wait()
+	s.session.Close()
+	s.client.Close()
+	return waitErr
+}
+
+func sshUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func sshAgentSigners() ([]ssh.Signer, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK not set; is ssh-agent running?")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+	return agent.NewClient(conn).Signers()
+}
+
+func withDefaultSSHPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "22")
+}
@@ -0,0 +1,46 @@
+// +build ssh
+
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// package main's ssh fetcher is only compiled in (and these tests only run)
+// with `go test -tags ssh ./...`; a plain `go test ./...` skips this file
+// entirely, so CI must include the tagged run to get ssh coverage.
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSSHClient struct {
+	files map[string]sshObject
+}
+
+func (f fakeSSHClient) ReadFile(host, path string) (sshObject, error) {
+	return f.files[host+path], nil
+}
+
+func TestSSHFetcherRecordsHostKeyFingerprint(t *testing.T) {
+	client := fakeSSHClient{files: map[string]sshObject{
+		"example.com/data.bin": {
+			Body:               ioutil.NopCloser(bytes.NewBufferString("abcdef")),
+			HostKeyFingerprint: "SHA256:abc123",
+		},
+	}}
+
+	r, meta, unchanged, err := sshFetcher{client: client}.Fetch(context.Background(), "ssh://example.com/data.bin")
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+	assert.Equal(t, types.String("SHA256:abc123"), meta["sshHostKeyFingerprint"])
+
+	body, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef", string(body))
+}